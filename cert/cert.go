@@ -0,0 +1,281 @@
+// Package cert implements a hot-reloadable, multi-certificate TLS manager.
+//
+// Certificates are loaded from a directory tree such as:
+//
+//	~/.dbhub/certs/
+//	├── db.example.org/
+//	│   ├── public.crt
+//	│   └── private.key
+//	└── www.example.org/
+//	    ├── public.crt
+//	    └── private.key
+//
+// Each subdirectory is expected to hold exactly one public.crt/private.key
+// pair.  The subdirectory name isn't read by the manager; only the Subject
+// Alternative Names (and CN, for older certs) embedded in the certificate
+// itself are used for matching.
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	publicCertFile = "public.crt"
+	privateKeyFile = "private.key"
+	pollInterval   = 30 * time.Second
+)
+
+// entry pairs a parsed certificate with the names it's valid for, so
+// GetCertificate doesn't need to re-parse the leaf on every handshake.
+type entry struct {
+	cert  *tls.Certificate
+	names []string // DNS SANs and CN
+	ips   []net.IP // IP SANs
+}
+
+// Manager watches a directory of certificate subdirectories and serves the
+// right leaf certificate for incoming TLS handshakes, based on SNI (falling
+// back to SAN/IP matching against the connection's local address when the
+// client doesn't send SNI).
+type Manager struct {
+	dir string
+
+	mu      sync.RWMutex
+	entries []entry
+
+	watcher *fsnotify.Watcher
+	sighup  chan os.Signal
+	done    chan struct{}
+}
+
+// NewManager scans dir for certificate pairs and returns a Manager ready to
+// be plugged into a tls.Config via GetCertificate. The directory is scanned
+// immediately, so NewManager returns an error if no usable certificates are
+// found.
+func NewManager(dir string) (*Manager, error) {
+	m := &Manager{
+		dir:    dir,
+		sighup: make(chan os.Signal, 1),
+		done:   make(chan struct{}),
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload rescans the certificate directory and atomically swaps the
+// in-memory certificate set. Existing connections keep using whatever leaf
+// they were handed; only new handshakes see the reloaded certificates.
+func (m *Manager) Reload() error {
+	var found []entry
+	err := filepath.Walk(m.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Base(path) != publicCertFile {
+			return nil
+		}
+		keyPath := filepath.Join(filepath.Dir(path), privateKeyFile)
+		if _, err := os.Stat(keyPath); err != nil {
+			// A public.crt without a matching private.key isn't usable, skip it
+			return nil
+		}
+		leaf, err := tls.LoadX509KeyPair(path, keyPath)
+		if err != nil {
+			return fmt.Errorf("couldn't load cert pair in %s: %v", filepath.Dir(path), err)
+		}
+		parsed, err := x509.ParseCertificate(leaf.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("couldn't parse cert in %s: %v", filepath.Dir(path), err)
+		}
+		e := entry{cert: &leaf, names: parsed.DNSNames, ips: parsed.IPAddresses}
+		if parsed.Subject.CommonName != "" {
+			e.names = append(e.names, parsed.Subject.CommonName)
+		}
+		found = append(found, e)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("scanning certificate directory %s: %v", m.dir, err)
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("no usable certificate pairs found under %s", m.dir)
+	}
+
+	m.mu.Lock()
+	m.entries = found
+	m.mu.Unlock()
+	return nil
+}
+
+// GetCertificate implements the tls.Config.GetCertificate callback. It picks
+// the leaf certificate matching the ClientHello's SNI, falling back to
+// matching the SAN/CN list against the connection's local host or IP when
+// SNI wasn't sent.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.entries) == 0 {
+		return nil, fmt.Errorf("no certificates loaded")
+	}
+
+	if hello.ServerName != "" {
+		if c := m.matchName(hello.ServerName); c != nil {
+			return c, nil
+		}
+	}
+
+	// No SNI (or no match on it) - fall back to matching against the host or
+	// IP the client actually connected to.
+	if hello.Conn != nil {
+		host, _, err := net.SplitHostPort(hello.Conn.LocalAddr().String())
+		if err == nil {
+			if c := m.matchName(host); c != nil {
+				return c, nil
+			}
+			if ip := net.ParseIP(host); ip != nil {
+				if c := m.matchIP(ip); c != nil {
+					return c, nil
+				}
+			}
+		}
+	}
+
+	// Still nothing - hand back the first certificate we have, so the
+	// handshake at least has a chance of completing instead of failing outright.
+	return m.entries[0].cert, nil
+}
+
+func (m *Manager) matchName(name string) *tls.Certificate {
+	for _, e := range m.entries {
+		for _, n := range e.names {
+			if certNameMatches(n, name) {
+				return e.cert
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Manager) matchIP(ip net.IP) *tls.Certificate {
+	for _, e := range m.entries {
+		for _, eip := range e.ips {
+			if eip.Equal(ip) {
+				return e.cert
+			}
+		}
+	}
+	return nil
+}
+
+// Watch starts watching the certificate directory for changes (new/removed
+// files, modified certs) and reloads the in-memory certificate set whenever
+// something changes. It also reloads on receipt of SIGHUP, so operators can
+// trigger a rotation manually after dropping new files in place. Watch
+// blocks until Close is called, so callers should run it in its own
+// goroutine.
+func (m *Manager) Watch() {
+	signal.Notify(m.sighup, syscall.SIGHUP)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		m.watcher = watcher
+		addWatchDirs(watcher, m.dir)
+	} else {
+		log.Printf("cert: couldn't start filesystem watcher, falling back to polling every %v: %v", pollInterval, err)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			if m.watcher != nil {
+				m.watcher.Close()
+			}
+			signal.Stop(m.sighup)
+			return
+		case <-m.sighup:
+			log.Println("cert: SIGHUP received, reloading certificates")
+			if err := m.Reload(); err != nil {
+				log.Printf("cert: reload failed: %v", err)
+			}
+		case <-ticker.C:
+			if err := m.Reload(); err != nil {
+				log.Printf("cert: periodic reload failed: %v", err)
+			}
+		case ev, ok := <-watcherEvents(m.watcher):
+			if !ok {
+				continue
+			}
+			log.Printf("cert: %s changed, reloading certificates", ev.Name)
+			if err := m.Reload(); err != nil {
+				log.Printf("cert: reload failed: %v", err)
+			}
+		}
+	}
+}
+
+// Close stops the watcher goroutine started by Watch.
+func (m *Manager) Close() {
+	close(m.done)
+}
+
+// watcherEvents returns w.Events, or a nil channel (which blocks forever in
+// a select) when w is nil, so Watch's select statement works whether or not
+// fsnotify was available.
+func watcherEvents(w *fsnotify.Watcher) chan fsnotify.Event {
+	if w == nil {
+		return nil
+	}
+	return w.Events
+}
+
+// addWatchDirs recursively adds dir and every subdirectory to the watcher,
+// since fsnotify doesn't watch recursively on its own.
+func addWatchDirs(w *fsnotify.Watcher, dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			w.Add(path)
+		}
+		return nil
+	})
+}
+
+// certNameMatches compares a certificate name (which may be a wildcard, e.g.
+// "*.example.org") against the requested host.
+func certNameMatches(certName, host string) bool {
+	certName = normalizeHost(certName)
+	host = normalizeHost(host)
+	if certName == host {
+		return true
+	}
+	if len(certName) > 1 && certName[0] == '*' {
+		suffix := certName[1:] // ".example.org"
+		return len(host) > len(suffix) && host[len(host)-len(suffix):] == suffix
+	}
+	return false
+}
+
+func normalizeHost(h string) string {
+	if len(h) > 0 && h[len(h)-1] == '.' {
+		h = h[:len(h)-1]
+	}
+	return h
+}
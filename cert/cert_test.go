@@ -0,0 +1,140 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSigned generates a self-signed certificate for host and writes
+// the public.crt/private.key pair into dir.
+func writeSelfSigned(t *testing.T, dir, host string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key for %s: %v", host, err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{host},
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating cert for %s: %v", host, err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("creating %s: %v", dir, err)
+	}
+
+	certOut, err := os.Create(filepath.Join(dir, publicCertFile))
+	if err != nil {
+		t.Fatalf("creating public.crt for %s: %v", host, err)
+	}
+	defer certOut.Close()
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("writing public.crt for %s: %v", host, err)
+	}
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshaling key for %s: %v", host, err)
+	}
+	keyOut, err := os.Create(filepath.Join(dir, privateKeyFile))
+	if err != nil {
+		t.Fatalf("creating private.key for %s: %v", host, err)
+	}
+	defer keyOut.Close()
+	if err := pem.Encode(keyOut, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}); err != nil {
+		t.Fatalf("writing private.key for %s: %v", host, err)
+	}
+}
+
+func TestManagerSNIDispatch(t *testing.T) {
+	root, err := ioutil.TempDir("", "dbhub-certs")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeSelfSigned(t, filepath.Join(root, "db.example.org"), "db.example.org")
+	writeSelfSigned(t, filepath.Join(root, "www.example.org"), "www.example.org")
+
+	m, err := NewManager(root)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	for _, host := range []string{"db.example.org", "www.example.org"} {
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: host})
+		if err != nil {
+			t.Fatalf("GetCertificate(%s): %v", host, err)
+		}
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("parsing returned cert for %s: %v", host, err)
+		}
+		if parsed.Subject.CommonName != host {
+			t.Errorf("GetCertificate(%s) returned cert for %s, wanted %s", host, parsed.Subject.CommonName, host)
+		}
+	}
+
+	// Unknown SNI falls back to the first loaded cert rather than erroring.
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "unknown.example.org"}); err != nil {
+		t.Errorf("GetCertificate(unknown): %v", err)
+	}
+}
+
+func TestManagerReloadPicksUpNewCert(t *testing.T) {
+	root, err := ioutil.TempDir("", "dbhub-certs")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeSelfSigned(t, filepath.Join(root, "db.example.org"), "db.example.org")
+
+	m, err := NewManager(root)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "new.example.org"}); err != nil {
+		t.Fatalf("GetCertificate before reload: %v", err)
+	}
+
+	writeSelfSigned(t, filepath.Join(root, "new.example.org"), "new.example.org")
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "new.example.org"})
+	if err != nil {
+		t.Fatalf("GetCertificate after reload: %v", err)
+	}
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parsing returned cert: %v", err)
+	}
+	if parsed.Subject.CommonName != "new.example.org" {
+		t.Errorf("Reload didn't pick up new.example.org, got cert for %s", parsed.Subject.CommonName)
+	}
+}
@@ -0,0 +1,306 @@
+// Package config loads and validates DBHub's on-disk TOML configuration.
+//
+// Every leaf field can be overridden by an environment variable derived from
+// its position in the struct: DBHUB_<SECTION>_<FIELD>, using each field's
+// `toml` tag (or its Go name, upper-cased, if no tag is set). For example
+// Web.ShutdownTimeout is overridden by DBHUB_WEB_SHUTDOWN_TIMEOUT, and
+// Pg.Port by DBHUB_PG_PORT. This means new fields automatically get an
+// env var override without any code changes here.
+//
+// Fields tagged `required:"true"` are checked after the file and environment
+// are merged; Load/Reload return a single *ValidationError listing every
+// problem found, along with the TOML key and env var that would fix it.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/minio/go-homedir"
+)
+
+const envPrefix = "DBHUB"
+
+// TomlConfig is the root of DBHub's configuration file.
+type TomlConfig struct {
+	Storage       StorageInfo
+	Pg            PgInfo
+	DataGen       DataGenInfo
+	Web           WebInfo
+	Observability ObservabilityInfo
+}
+
+// StorageInfo selects and configures DBHub's object storage driver. Only the
+// section matching Driver needs to be filled in.
+type StorageInfo struct {
+	Driver string    `toml:"driver" required:"true"` // "minio", "s3v4" or "fs"
+	Minio  MinioInfo `toml:"minio"`
+	S3     S3Info    `toml:"s3"`
+	FS     FSInfo    `toml:"fs"`
+}
+
+// MinioInfo holds the Minio connection parameters.
+type MinioInfo struct {
+	Server    string `toml:"server"`
+	AccessKey string `toml:"access_key"`
+	Secret    string `toml:"secret"`
+	HTTPS     bool   `toml:"https"`
+}
+
+// S3Info holds the connection parameters for talking to real AWS S3.
+type S3Info struct {
+	Region    string `toml:"region"`
+	Bucket    string `toml:"bucket"`
+	AccessKey string `toml:"access_key"`
+	Secret    string `toml:"secret"`
+}
+
+// FSInfo holds the connection parameters for the filesystem storage driver.
+type FSInfo struct {
+	Root string `toml:"root"`
+}
+
+// PgInfo holds the PostgreSQL connection parameters.
+//
+// Password isn't marked required, as the PostgreSQL password can also be
+// kept in a .pgpass file as per
+// https://www.postgresql.org/docs/current/static/libpq-pgpass.html
+type PgInfo struct {
+	Server   string `toml:"server" required:"true"`
+	Port     int    `toml:"port" required:"true"`
+	Username string `toml:"username" required:"true"`
+	Password string `toml:"password"`
+	Database string `toml:"database" required:"true"`
+}
+
+// DataGenInfo holds configuration info for the data generator.
+type DataGenInfo struct {
+	Server         string `toml:"server"`
+	HTTPS          bool   `toml:"https"`
+	Certificate    string `toml:"certificate"`
+	CertificateKey string `toml:"certificate_key"`
+}
+
+// WebInfo holds configuration info for the DBHub web server.
+type WebInfo struct {
+	Server          string `toml:"server" required:"true"`
+	CertDir         string `toml:"certificate_dir" required:"true"`
+	ShutdownTimeout string `toml:"shutdown_timeout"`
+}
+
+// ObservabilityInfo configures DBHub's metrics and logging.
+type ObservabilityInfo struct {
+	// MetricsBind is the address /metrics is served on, e.g. "127.0.0.1:9090".
+	// Left empty, the metrics endpoint isn't started at all.
+	MetricsBind string `toml:"metrics_bind"`
+	// Dev switches the logger from JSON to human-readable output.
+	Dev bool `toml:"dev"`
+}
+
+// FieldProblem describes a single missing or invalid configuration field.
+type FieldProblem struct {
+	TomlPath string // dotted TOML key, e.g. "pg.port"
+	EnvVar   string // e.g. DBHUB_PG_PORT
+	Message  string
+}
+
+// ValidationError aggregates every FieldProblem found while validating a
+// config, so operators can fix everything in one pass instead of being told
+// about one missing field at a time.
+type ValidationError struct {
+	Problems []FieldProblem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Missing or incomplete configuration value(s):\n")
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n \t→ %s (toml key %q, or set %s)", p.Message, p.TomlPath, p.EnvVar)
+	}
+	return b.String()
+}
+
+var (
+	mu      sync.RWMutex
+	current *TomlConfig
+)
+
+// Current returns the most recently loaded configuration. It's nil until
+// Load or Reload has succeeded at least once.
+func Current() *TomlConfig {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Load reads the configuration file from ~/.dbhub/config.toml, applies any
+// DBHUB_* environment variable overrides, validates the result and - if
+// everything checks out - stores it so future calls to Current return it.
+func Load() (*TomlConfig, error) {
+	userHome, err := homedir.Dir()
+	if err != nil {
+		return nil, fmt.Errorf("user home directory couldn't be determined: %v", err)
+	}
+	return load(filepath.Join(userHome, ".dbhub", "config.toml"))
+}
+
+// Reload re-reads the configuration file and environment, returning the new
+// config on success. It's meant to be called in response to SIGHUP; callers
+// are responsible for deciding whether/how to apply the new value to a
+// running server.
+func Reload() (*TomlConfig, error) {
+	return Load()
+}
+
+func load(configFile string) (*TomlConfig, error) {
+	var cfg TomlConfig
+	if _, err := toml.DecodeFile(configFile, &cfg); err != nil {
+		return nil, fmt.Errorf("config file couldn't be parsed: %v", err)
+	}
+
+	applyEnvOverrides(reflect.ValueOf(&cfg).Elem(), nil)
+
+	problems := validate(reflect.ValueOf(&cfg).Elem(), nil)
+	problems = append(problems, validateStorage(cfg.Storage)...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	mu.Lock()
+	current = &cfg
+	mu.Unlock()
+	return &cfg, nil
+}
+
+// applyEnvOverrides walks v (a struct) recursively, overriding each leaf
+// field from its derived DBHUB_* environment variable when one is set.
+func applyEnvOverrides(v reflect.Value, path []string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		fieldPath := append(append([]string{}, path...), tomlKey(field))
+
+		if fv.Kind() == reflect.Struct {
+			applyEnvOverrides(fv, fieldPath)
+			continue
+		}
+
+		envVar := envName(fieldPath)
+		raw, ok := os.LookupEnv(envVar)
+		if !ok || raw == "" {
+			continue
+		}
+		setField(fv, raw)
+	}
+}
+
+// validate walks v (a struct) recursively, returning one FieldProblem for
+// every field tagged `required:"true"` that's still at its zero value.
+func validate(v reflect.Value, path []string) []FieldProblem {
+	var problems []FieldProblem
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		fieldPath := append(append([]string{}, path...), tomlKey(field))
+
+		if fv.Kind() == reflect.Struct {
+			problems = append(problems, validate(fv, fieldPath)...)
+			continue
+		}
+
+		if field.Tag.Get("required") != "true" {
+			continue
+		}
+		if fv.IsZero() {
+			problems = append(problems, FieldProblem{
+				TomlPath: strings.Join(fieldPath, "."),
+				EnvVar:   envName(fieldPath),
+				Message:  fmt.Sprintf("%s is required", strings.Join(fieldPath, ".")),
+			})
+		}
+	}
+	return problems
+}
+
+// validateStorage checks the driver-specific section of Storage, since
+// which fields are required depends on which driver was chosen - something
+// the generic tag-based validate() above can't express.
+func validateStorage(s StorageInfo) []FieldProblem {
+	requireString := func(path []string, value string) []FieldProblem {
+		if value != "" {
+			return nil
+		}
+		return []FieldProblem{{
+			TomlPath: strings.Join(path, "."),
+			EnvVar:   envName(path),
+			Message:  fmt.Sprintf("%s is required", strings.Join(path, ".")),
+		}}
+	}
+
+	var problems []FieldProblem
+	switch s.Driver {
+	case "minio":
+		problems = append(problems, requireString([]string{"storage", "minio", "server"}, s.Minio.Server)...)
+		problems = append(problems, requireString([]string{"storage", "minio", "access_key"}, s.Minio.AccessKey)...)
+		problems = append(problems, requireString([]string{"storage", "minio", "secret"}, s.Minio.Secret)...)
+	case "s3v4":
+		problems = append(problems, requireString([]string{"storage", "s3", "region"}, s.S3.Region)...)
+		problems = append(problems, requireString([]string{"storage", "s3", "bucket"}, s.S3.Bucket)...)
+		problems = append(problems, requireString([]string{"storage", "s3", "access_key"}, s.S3.AccessKey)...)
+		problems = append(problems, requireString([]string{"storage", "s3", "secret"}, s.S3.Secret)...)
+	case "fs":
+		problems = append(problems, requireString([]string{"storage", "fs", "root"}, s.FS.Root)...)
+	case "":
+		// Driver itself is already flagged as missing by validate()'s
+		// required tag; no need to also complain about its subsections.
+	default:
+		problems = append(problems, FieldProblem{
+			TomlPath: "storage.driver",
+			EnvVar:   envName([]string{"storage", "driver"}),
+			Message:  fmt.Sprintf("storage.driver %q is not one of: minio, s3v4, fs", s.Driver),
+		})
+	}
+	return problems
+}
+
+func setField(fv reflect.Value, raw string) {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			fv.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			fv.SetInt(n)
+		}
+	}
+}
+
+// tomlKey returns the TOML key a struct field is decoded from: its `toml`
+// tag if set, otherwise its lower-cased field name.
+func tomlKey(field reflect.StructField) string {
+	if tag := field.Tag.Get("toml"); tag != "" {
+		return tag
+	}
+	return strings.ToLower(field.Name)
+}
+
+// envName derives the DBHUB_* environment variable for a field path, e.g.
+// ["web", "session_secret"] -> "DBHUB_WEB_SESSION_SECRET".
+func envName(path []string) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = strings.ToUpper(p)
+	}
+	return envPrefix + "_" + strings.Join(parts, "_")
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testTOML = `
+[Storage]
+driver = "minio"
+
+[Storage.Minio]
+server = "minio.example.org:9000"
+access_key = "access"
+secret = "secret"
+
+[Pg]
+server = "pg.example.org"
+port = 5432
+username = "dbhub"
+database = "dbhub"
+
+[Web]
+server = "0.0.0.0:443"
+certificate_dir = "/etc/dbhub/certs"
+`
+
+func writeTempConfig(t *testing.T, contents string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "dbhub-config")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("writing config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadValidConfig(t *testing.T) {
+	path := writeTempConfig(t, testTOML)
+
+	cfg, err := load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Pg.Port != 5432 {
+		t.Errorf("Pg.Port = %d, want 5432", cfg.Pg.Port)
+	}
+	if Current() != cfg {
+		t.Errorf("Current() didn't return the just-loaded config")
+	}
+}
+
+func TestLoadReportsAllMissingFields(t *testing.T) {
+	path := writeTempConfig(t, `
+[Web]
+server = "0.0.0.0:443"
+certificate_dir = "/etc/dbhub/certs"
+`)
+
+	_, err := load(path)
+	if err == nil {
+		t.Fatal("load: expected an error for missing Storage/Pg fields")
+	}
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("load: error was %T, want *ValidationError", err)
+	}
+
+	want := map[string]string{
+		"storage.driver": "DBHUB_STORAGE_DRIVER",
+		"pg.server":      "DBHUB_PG_SERVER",
+		"pg.port":        "DBHUB_PG_PORT",
+		"pg.username":    "DBHUB_PG_USERNAME",
+		"pg.database":    "DBHUB_PG_DATABASE",
+	}
+	got := map[string]string{}
+	for _, p := range verr.Problems {
+		got[p.TomlPath] = p.EnvVar
+	}
+	for path, envVar := range want {
+		if got[path] != envVar {
+			t.Errorf("missing problem for %s (want env var %s), got %s", path, envVar, got[path])
+		}
+	}
+}
+
+func TestLoadValidatesSelectedStorageDriver(t *testing.T) {
+	path := writeTempConfig(t, `
+[Storage]
+driver = "fs"
+
+[Pg]
+server = "pg.example.org"
+port = 5432
+username = "dbhub"
+database = "dbhub"
+
+[Web]
+server = "0.0.0.0:443"
+certificate_dir = "/etc/dbhub/certs"
+`)
+
+	_, err := load(path)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("load: error was %T, want *ValidationError for missing storage.fs.root", err)
+	}
+	if len(verr.Problems) != 1 || verr.Problems[0].TomlPath != "storage.fs.root" {
+		t.Errorf("Problems = %+v, want a single storage.fs.root problem", verr.Problems)
+	}
+}
+
+func TestEnvOverride(t *testing.T) {
+	path := writeTempConfig(t, testTOML)
+
+	os.Setenv("DBHUB_PG_PORT", "6543")
+	defer os.Unsetenv("DBHUB_PG_PORT")
+
+	cfg, err := load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Pg.Port != 6543 {
+		t.Errorf("Pg.Port = %d, want 6543 from DBHUB_PG_PORT override", cfg.Pg.Port)
+	}
+}
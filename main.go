@@ -1,211 +1,220 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
-	"github.com/BurntSushi/toml"
 	"github.com/jackc/pgx"
-	"github.com/minio/go-homedir"
-	"github.com/minio/minio-go"
+	"github.com/mwiemarc/DBHub.io-website/cert"
+	"github.com/mwiemarc/DBHub.io-website/config"
+	"github.com/mwiemarc/DBHub.io-website/observability"
+	"github.com/mwiemarc/DBHub.io-website/server"
+	"github.com/mwiemarc/DBHub.io-website/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"log"
 	"net/http"
-	"path/filepath"
 	"os"
-	"strconv"
-	"log"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
-// Configuration file
-type tomlConfig struct {
-	Minio   minioInfo
-	Pg      pgInfo
-	DataGen dataGenInfo
-	Web     webInfo
-}
-
-var conf tomlConfig
+// defaultShutdownTimeout is used when Web.ShutdownTimeout isn't set in the
+// config file.
+const defaultShutdownTimeout = 30 * time.Second
 
-// Minio connection parameters
-type minioInfo struct {
-	Server    string
-	AccessKey string `toml:"access_key"`
-	Secret    string
-	HTTPS     bool
-}
+// stateMu guards db and objectStore, which a SIGHUP config reload swaps out
+// from under the running server.
+var (
+	stateMu     sync.RWMutex
+	db          *pgx.Conn
+	objectStore storage.Backend
+)
 
-// PostgreSQL connection parameters
-type pgInfo struct {
-	Server   string
-	Port     int
-	Username string
-	Password string
-	Database string
+func currentDB() *pgx.Conn {
+	stateMu.RLock()
+	defer stateMu.RUnlock()
+	return db
 }
 
-// Configuration info for the data generator
-type dataGenInfo struct {
-	Server         string
-	HTTPS          bool
-	Certificate    string
-	CertificateKey string `toml:"certificate_key"`
+func currentObjectStore() storage.Backend {
+	stateMu.RLock()
+	defer stateMu.RUnlock()
+	return objectStore
 }
 
-// Configuration info just for us
-type webInfo struct {
-	Server         string
-	Certificate    string
-	CertificateKey string `toml:"certificate_key"`
+// setState installs newDB/newStore as the live database connection and
+// storage backend, returning whatever they replace so the caller can close
+// them down.
+func setState(newDB *pgx.Conn, newStore storage.Backend) (oldDB *pgx.Conn, oldStore storage.Backend) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	oldDB, oldStore = db, objectStore
+	db, objectStore = newDB, newStore
+	return
 }
 
-var minioClient *minio.Client
-var pgConfig = new(pgx.ConnConfig)
-
-// Database connection
-var db *pgx.Conn
+var logger *observability.Logger
+var metrics *observability.Metrics
 
 func main() {
 	// Read server configuration
-	var err error
-	if err = readConfig(); err != nil {
+	conf, err := config.Load()
+	if err != nil {
 		log.Fatalf("Configuration file problem\n\n%v", err)
 	}
 
-	// Connect to Minio server
-	minioClient, err = minio.New(conf.Minio.Server, conf.Minio.AccessKey, conf.Minio.Secret, conf.Minio.HTTPS)
-	if err != nil {
-		log.Fatalf("Problem with Minio server configuration: \n\n%v", err)
+	logger = observability.NewLogger(conf.Observability.Dev)
+	ctx := context.Background()
+
+	// Start the metrics registry and, if configured, its HTTP endpoint
+	registry := prometheus.NewRegistry()
+	metrics = observability.NewMetrics(registry)
+	if conf.Observability.MetricsBind != "" {
+		go func() {
+			logger.Info(ctx, "starting metrics endpoint", observability.F("bind", conf.Observability.MetricsBind))
+			if err := http.ListenAndServe(conf.Observability.MetricsBind, observability.Handler(registry)); err != nil {
+				logger.Error(ctx, "metrics endpoint stopped", observability.F("error", err))
+			}
+		}()
 	}
 
-	// Log Minio server end point
-	log.Printf("Minio server config ok: %v\n", conf.Minio.Server)
+	// Set up the object storage backend (Minio, S3 or filesystem, per
+	// Storage.Driver) and make sure it's actually reachable before going
+	// any further
+	newStore, err := storage.New(storageConfig(conf.Storage))
+	if err != nil {
+		logger.Fatal(ctx, "storage configuration problem", observability.F("error", err))
+	}
+	if err = newStore.HealthCheck(); err != nil {
+		logger.Fatal(ctx, "storage health check failed", observability.F("error", err))
+	}
+	logger.Info(ctx, "storage backend ok", observability.F("driver", conf.Storage.Driver))
 
 	// Connect to PostgreSQL server
-	db, err = pgx.Connect(*pgConfig)
-	defer db.Close()
+	newDB, err := pgx.Connect(pgConfigFrom(conf))
 	if err != nil {
-		log.Fatalf("Couldn't connect to database\n\n%v", err)
+		logger.Fatal(ctx, "couldn't connect to database", observability.F("error", err))
 	}
+	logger.Info(ctx, "connected to PostgreSQL server", observability.F("server", conf.Pg.Server), observability.F("port", conf.Pg.Port))
 
-	// Log successful connection message
-	log.Printf("Connected to PostgreSQL server: %v:%v\n", conf.Pg.Server, uint16(conf.Pg.Port))
-
-	// URL handlers
-	http.HandleFunc("/", rootHandler)
+	setState(newDB, newStore)
 
-	// Start server
-	log.Printf("Starting DBHub webserver on https://%s\n", conf.Web.Server)
-	log.Fatal(http.ListenAndServeTLS(conf.Web.Server, conf.Web.Certificate, conf.Web.CertificateKey, nil))
-}
+	// Reload the configuration on SIGHUP, so operators don't need to restart
+	// DBHub to pick up config file changes
+	go watchConfigReloads()
 
-// Read the server configuration file
-func readConfig() error {
-	// Reads the server configuration from disk
-	// TODO: Might be a good idea to add permission checks of the dir & conf file, to ensure they're not
-	// TODO: world readable
-	userHome, err := homedir.Dir()
+	// Load the TLS certificates and start watching the cert directory for
+	// changes, so operators can rotate certs without restarting DBHub
+	certManager, err := cert.NewManager(conf.Web.CertDir)
 	if err != nil {
-		return fmt.Errorf("User home directory couldn't be determined: %s", "\n")
-	}
-	configFile := filepath.Join(userHome, ".dbhub", "config.toml")
-	if _, err := toml.DecodeFile(configFile, &conf); err != nil {
-		return fmt.Errorf("Config file couldn't be parsed: %v\n", err)
+		logger.Fatal(ctx, "problem loading TLS certificates", observability.F("error", err))
 	}
+	go certManager.Watch()
+
+	// URL handlers, wrapped in the observability middleware so every
+	// request gets a request ID, gets timed, and shows up in metrics
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", rootHandler)
 
-	// Override config file via environment variables
-	tempString := os.Getenv("MINIO_SERVER")
-	if tempString != "" {
-		conf.Minio.Server = tempString
+	// Start server, with graceful shutdown on SIGINT/SIGTERM and
+	// zero-downtime restarts on SIGUSR1
+	shutdownTimeout := defaultShutdownTimeout
+	if conf.Web.ShutdownTimeout != "" {
+		shutdownTimeout, err = time.ParseDuration(conf.Web.ShutdownTimeout)
+		if err != nil {
+			logger.Fatal(ctx, "couldn't parse Web.ShutdownTimeout", observability.F("error", err))
+		}
 	}
-	tempString = os.Getenv("MINIO_ACCESS_KEY")
-	if tempString != "" {
-		conf.Minio.AccessKey = tempString
+	srv, err := server.New(conf.Web.Server, &tls.Config{GetCertificate: certManager.GetCertificate}, observability.Middleware(logger, metrics, mux), shutdownTimeout)
+	if err != nil {
+		logger.Fatal(ctx, "couldn't start webserver", observability.F("error", err))
 	}
-	tempString = os.Getenv("MINIO_SECRET")
-	if tempString != "" {
-		conf.Minio.Secret = tempString
+	// Close the live database connection once in-flight handlers have
+	// drained, rather than relying on a bare defer that never runs for the
+	// connection a SIGHUP reload may have swapped in.
+	srv.OnShutdown(func() error {
+		return currentDB().Close()
+	})
+
+	logger.Info(ctx, "starting DBHub webserver", observability.F("addr", conf.Web.Server))
+	if err = srv.Run(); err != nil {
+		logger.Fatal(ctx, "webserver error", observability.F("error", err))
 	}
-	tempString = os.Getenv("MINIO_HTTPS")
-	if tempString != "" {
-		conf.Minio.HTTPS, err = strconv.ParseBool(tempString)
+}
+
+// watchConfigReloads reloads the configuration file whenever DBHub receives
+// SIGHUP, reopening the database connection and storage backend so the
+// running server actually picks up the new values, rather than just
+// re-reading the file without applying it.
+func watchConfigReloads() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	ctx := context.Background()
+	for range sighup {
+		newConf, err := config.Reload()
 		if err != nil {
-			return fmt.Errorf("Failed to parse MINIO_HTTPS: %v\n", err)
+			logger.Error(ctx, "config reload failed, keeping existing configuration", observability.F("error", err))
+			continue
 		}
-	}
-	tempString = os.Getenv("PG_SERVER")
-	if tempString != "" {
-		conf.Pg.Server = tempString
-	}
-	tempString = os.Getenv("PG_PORT")
-	if tempString != "" {
-		tempInt, err := strconv.ParseInt(tempString, 10, 0)
+
+		newStore, err := storage.New(storageConfig(newConf.Storage))
 		if err != nil {
-			return fmt.Errorf("Failed to parse PG_PORT: %v\n", err)
+			logger.Error(ctx, "config reload: storage configuration problem, keeping existing backend", observability.F("error", err))
+			continue
+		}
+		if err = newStore.HealthCheck(); err != nil {
+			logger.Error(ctx, "config reload: storage health check failed, keeping existing backend", observability.F("error", err))
+			continue
 		}
-		conf.Pg.Port = int(tempInt)
-	}
-	tempString = os.Getenv("PG_USER")
-	if tempString != "" {
-		conf.Pg.Username = tempString
-	}
-	tempString = os.Getenv("PG_PASS")
-	if tempString != "" {
-		conf.Pg.Password = tempString
-	}
-	tempString = os.Getenv("PG_DBNAME")
-	if tempString != "" {
-		conf.Pg.Database = tempString
-	}
 
-	// Verify we have the needed configuration information
-	// Note - We don't check for a valid conf.Pg.Password here, as the PostgreSQL password can also be kept
-	// in a .pgpass file as per https://www.postgresql.org/docs/current/static/libpq-pgpass.html
-	var missingConfig []string
-	if conf.Minio.Server == "" {
-		missingConfig = append(missingConfig, "Minio server:port string")
-	}
-	if conf.Minio.AccessKey == "" {
-		missingConfig = append(missingConfig, "Minio access key string")
-	}
-	if conf.Minio.Secret == "" {
-		missingConfig = append(missingConfig, "Minio secret string")
-	}
-	if conf.Pg.Server == "" {
-		missingConfig = append(missingConfig, "PostgreSQL server string")
-	}
-	if conf.Pg.Port == 0 {
-		missingConfig = append(missingConfig, "PostgreSQL port number")
-	}
-	if conf.Pg.Username == "" {
-		missingConfig = append(missingConfig, "PostgreSQL username string")
-	}
-	if conf.Pg.Password == "" {
-		missingConfig = append(missingConfig, "PostgreSQL password string")
-	}
-	if conf.Pg.Database == "" {
-		missingConfig = append(missingConfig, "PostgreSQL database string")
-	}
-	if len(missingConfig) > 0 {
-		// Some config is missing
-		returnMessage := fmt.Sprint("Missing or incomplete value(s):\n")
-		for _, value := range missingConfig {
-			returnMessage += fmt.Sprintf("\n \tâ†’ %v", value)
+		newDB, err := pgx.Connect(pgConfigFrom(newConf))
+		if err != nil {
+			logger.Error(ctx, "config reload: couldn't connect to database, keeping existing connection", observability.F("error", err))
+			continue
+		}
+
+		oldDB, _ := setState(newDB, newStore)
+		if oldDB != nil {
+			if err := oldDB.Close(); err != nil {
+				logger.Error(ctx, "config reload: error closing previous database connection", observability.F("error", err))
+			}
 		}
-		return fmt.Errorf(returnMessage)
+		logger.Info(ctx, "configuration reloaded")
 	}
+}
 
-	// Set the PostgreSQL configuration values
-	pgConfig.Host = conf.Pg.Server
-	pgConfig.Port = uint16(conf.Pg.Port)
-	pgConfig.User = conf.Pg.Username
-	pgConfig.Password = conf.Pg.Password
-	pgConfig.Database = conf.Pg.Database
-	pgConfig.TLSConfig = nil
+// pgConfigFrom builds the PostgreSQL connection parameters for a given
+// config. It returns a plain value rather than mutating shared state, so
+// concurrent reloads can't race with each other or with the initial connect.
+func pgConfigFrom(c *config.TomlConfig) pgx.ConnConfig {
+	return pgx.ConnConfig{
+		Host:      c.Pg.Server,
+		Port:      uint16(c.Pg.Port),
+		User:      c.Pg.Username,
+		Password:  c.Pg.Password,
+		Database:  c.Pg.Database,
+		TLSConfig: nil,
+	}
+}
 
-	// The configuration file seems good
-	return nil
+// storageConfig maps the config file's Storage section onto storage.Config,
+// which keeps the storage package decoupled from how DBHub's config file
+// happens to be structured.
+func storageConfig(c config.StorageInfo) storage.Config {
+	return storage.Config{
+		Driver: c.Driver,
+		Minio:  storage.MinioConfig{Server: c.Minio.Server, AccessKey: c.Minio.AccessKey, Secret: c.Minio.Secret, HTTPS: c.Minio.HTTPS},
+		S3:     storage.S3Config{Region: c.S3.Region, Bucket: c.S3.Bucket, AccessKey: c.S3.AccessKey, Secret: c.S3.Secret},
+		FS:     storage.FSConfig{Root: c.FS.Root},
+	}
 }
 
 func rootHandler(w http.ResponseWriter, req *http.Request) {
 
 	// TODO: Everything :)
 
+	logger.Info(req.Context(), "handling request")
 	fmt.Fprintln(w, "Stuff goes here")
-}
\ No newline at end of file
+}
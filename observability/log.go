@@ -0,0 +1,122 @@
+package observability
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ctxKey int
+
+const requestIDKey ctxKey = 0
+
+// NewRequestID generates a short, URL-safe identifier to tag a single
+// request's log lines. It travels in the request's context.Context; the
+// storage.Backend methods take no context, so storage calls don't carry it.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand failing means something is very wrong with the
+		// system, but a missing request ID shouldn't take the server down
+		return "unavailable"
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a copy of ctx carrying id, retrievable via
+// RequestIDFromContext.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID stashed by WithRequestID, or
+// "" if ctx doesn't carry one.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Field is a single structured logging key/value pair.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F builds a Field - shorthand used at call sites, e.g. F("status", 200).
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger emits structured log lines, attaching the request ID from ctx (if
+// any) to every line. In JSON mode it's meant for production log
+// aggregation; in dev mode it's a human-readable single line.
+type Logger struct {
+	json bool
+}
+
+// NewLogger returns a Logger. When dev is true, log lines are emitted as
+// human-readable text instead of JSON.
+func NewLogger(dev bool) *Logger {
+	return &Logger{json: !dev}
+}
+
+// Info logs msg at info level, along with fields and the request ID from
+// ctx (if present).
+func (l *Logger) Info(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, "info", msg, fields)
+}
+
+// Error logs msg at error level, along with fields and the request ID from
+// ctx (if present).
+func (l *Logger) Error(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, "error", msg, fields)
+}
+
+// Fatal logs msg at fatal level and then exits the process, mirroring
+// log.Fatalf's behaviour for unrecoverable startup failures.
+func (l *Logger) Fatal(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, "fatal", msg, fields)
+	os.Exit(1)
+}
+
+func (l *Logger) log(ctx context.Context, level, msg string, fields []Field) {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		fields = append(fields, F("request_id", requestID))
+	}
+	if l.json {
+		fmt.Fprintln(os.Stderr, encodeJSON(level, msg, fields))
+		return
+	}
+	fmt.Fprintln(os.Stderr, encodeText(level, msg, fields))
+}
+
+func encodeJSON(level, msg string, fields []Field) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	fmt.Fprintf(&b, "%q:%q,", "time", time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "%q:%q,", "level", level)
+	fmt.Fprintf(&b, "%q:%q", "msg", msg)
+	for _, f := range fields {
+		fmt.Fprintf(&b, ",%q:%q", f.Key, fmt.Sprint(f.Value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func encodeText(level, msg string, fields []Field) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s] %s", time.Now().Format("15:04:05"), strings.ToUpper(level), msg)
+	// Sort so request_id (and everything else) prints in a stable order,
+	// which makes dev-mode log lines easier to diff/grep.
+	sorted := append([]Field{}, fields...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+	for _, f := range sorted {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	return b.String()
+}
@@ -0,0 +1,30 @@
+package observability
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestIDRoundTrip(t *testing.T) {
+	id := NewRequestID()
+	if id == "" {
+		t.Fatal("NewRequestID returned an empty string")
+	}
+
+	ctx := WithRequestID(context.Background(), id)
+	if got := RequestIDFromContext(ctx); got != id {
+		t.Errorf("RequestIDFromContext = %q, want %q", got, id)
+	}
+}
+
+func TestRequestIDFromContextEmptyByDefault(t *testing.T) {
+	if got := RequestIDFromContext(context.Background()); got != "" {
+		t.Errorf("RequestIDFromContext on a bare context = %q, want empty string", got)
+	}
+}
+
+func TestNewRequestIDIsUnique(t *testing.T) {
+	if NewRequestID() == NewRequestID() {
+		t.Error("NewRequestID returned the same value twice in a row")
+	}
+}
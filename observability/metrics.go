@@ -0,0 +1,80 @@
+package observability
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds every Prometheus collector DBHub exposes on /metrics.
+type Metrics struct {
+	httpRequestsTotal *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	pgQueryDuration   *prometheus.HistogramVec
+	minioOpDuration   *prometheus.HistogramVec
+	minioBytesTotal   *prometheus.CounterVec
+}
+
+// NewMetrics creates and registers DBHub's collectors against reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		httpRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dbhub_http_requests_total",
+			Help: "Total HTTP requests handled, by method, path and status code.",
+		}, []string{"method", "path", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "dbhub_http_request_duration_seconds",
+			Help:    "HTTP request latency, by method and path.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "path"}),
+		pgQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "dbhub_pg_query_duration_seconds",
+			Help:    "PostgreSQL query latency, by query name.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"query"}),
+		minioOpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "dbhub_minio_operation_duration_seconds",
+			Help:    "Minio/object storage operation latency, by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		minioBytesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dbhub_minio_bytes_transferred_total",
+			Help: "Bytes transferred to/from object storage, by operation.",
+		}, []string{"operation"}),
+	}
+	reg.MustRegister(m.httpRequestsTotal, m.httpDuration, m.pgQueryDuration, m.minioOpDuration, m.minioBytesTotal)
+	return m
+}
+
+// Handler returns the http.Handler to mount at /metrics.
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTP records one completed HTTP request.
+func (m *Metrics) ObserveHTTP(method, path string, status int, duration time.Duration) {
+	m.httpRequestsTotal.WithLabelValues(method, path, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, path).Observe(duration.Seconds())
+}
+
+// TimePgQuery runs fn, recording its duration under queryName. Intended to
+// wrap pgx QueryEx/ExecEx/QueryRowEx calls.
+func (m *Metrics) TimePgQuery(queryName string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	m.pgQueryDuration.WithLabelValues(queryName).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// TimeMinioOp runs fn, recording its duration and the number of bytes it
+// reports transferring under op.
+func (m *Metrics) TimeMinioOp(op string, fn func() (bytesTransferred int64, err error)) error {
+	start := time.Now()
+	n, err := fn()
+	m.minioOpDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	m.minioBytesTotal.WithLabelValues(op).Add(float64(n))
+	return err
+}
@@ -0,0 +1,57 @@
+package observability
+
+import (
+	"net/http"
+	"time"
+)
+
+// statusRecorder wraps an http.ResponseWriter to capture the status code
+// written, since http.ResponseWriter doesn't expose it afterwards.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// router is satisfied by *http.ServeMux. Handler returns the registered
+// pattern a request matches, which Middleware uses as its low-cardinality
+// metrics label instead of the raw, unbounded request path.
+type router interface {
+	http.Handler
+	Handler(r *http.Request) (http.Handler, string)
+}
+
+// Middleware wraps mux so every request gets a request ID (injected into
+// its context.Context, where the logger picks it up; the storage.Backend
+// methods take no context, so storage calls don't carry it), and has its
+// method/route/status/latency
+// recorded in metrics. The raw URL path is only ever logged, never used as
+// a metric label - that would give every distinct URL its own Prometheus
+// series.
+func Middleware(logger *Logger, metrics *Metrics, mux router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithRequestID(r.Context(), NewRequestID())
+		r = r.WithContext(ctx)
+
+		_, pattern := mux.Handler(r)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		mux.ServeHTTP(rec, r)
+
+		duration := time.Since(start)
+		metrics.ObserveHTTP(r.Method, pattern, rec.status, duration)
+		logger.Info(ctx, "http request",
+			F("method", r.Method),
+			F("path", r.URL.Path),
+			F("route", pattern),
+			F("status", rec.status),
+			F("duration_ms", duration.Milliseconds()),
+		)
+	})
+}
@@ -0,0 +1,157 @@
+// Package server manages the lifecycle of DBHub's HTTPS listener: graceful
+// shutdown on SIGINT/SIGTERM, and zero-downtime restarts on SIGUSR1 by
+// re-exec'ing the binary and handing the bound listening socket to the
+// child via ExtraFiles.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// listenerFDEnvVar, when set, tells a freshly exec'd process that fd 3 is
+// an already-bound listening socket inherited from its parent, rather than
+// one it should create itself.
+const listenerFDEnvVar = "DBHUB_LISTENER_FD"
+const inheritedFD = 3 // first fd after stdin/stdout/stderr, passed via ExtraFiles[0]
+
+// Server wraps an http.Server with graceful shutdown and zero-downtime
+// restart support.
+type Server struct {
+	http       *http.Server
+	raw        net.Listener // pre-TLS listener, kept so Restart can pass its FD on
+	serve      net.Listener // TLS-wrapped listener actually served on
+	shutdown   time.Duration
+	onShutdown func() error
+}
+
+// New creates a Server bound to addr with the given TLS config and handler.
+// If the process was re-exec'd by a previous DBHub instance for a
+// zero-downtime restart (DBHUB_LISTENER_FD is set), the inherited listener
+// is reused instead of binding a new one.
+func New(addr string, tlsConfig *tls.Config, handler http.Handler, shutdownTimeout time.Duration) (*Server, error) {
+	raw, err := listen(addr)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create listener: %v", err)
+	}
+
+	return &Server{
+		http:     &http.Server{Addr: addr, TLSConfig: tlsConfig, Handler: handler},
+		raw:      raw,
+		serve:    tls.NewListener(raw, tlsConfig),
+		shutdown: shutdownTimeout,
+	}, nil
+}
+
+func listen(addr string) (net.Listener, error) {
+	if os.Getenv(listenerFDEnvVar) != "" {
+		f := os.NewFile(uintptr(inheritedFD), "listener")
+		l, err := net.FileListener(f)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't inherit listener from fd %d: %v", inheritedFD, err)
+		}
+		f.Close()
+		return l, nil
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Serve starts accepting connections and blocks until the server is shut
+// down via Shutdown (directly, or as part of Run's signal handling).
+func (s *Server) Serve() error {
+	err := s.http.Serve(s.serve)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// OnShutdown registers fn to run once in-flight handlers have drained,
+// right before Shutdown returns - the hook point for closing things like
+// the database connection and flushing the storage client.
+func (s *Server) OnShutdown(fn func() error) {
+	s.onShutdown = fn
+}
+
+// Shutdown stops accepting new connections and waits up to the configured
+// shutdown timeout for in-flight handlers to finish, then runs the
+// OnShutdown hook (if one was registered) before returning.
+func (s *Server) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdown)
+	defer cancel()
+	err := s.http.Shutdown(ctx)
+	if s.onShutdown != nil {
+		if hookErr := s.onShutdown(); hookErr != nil && err == nil {
+			err = hookErr
+		}
+	}
+	return err
+}
+
+// Restart re-execs the current binary, passing the bound listening socket
+// to the child via ExtraFiles so it can start serving on the same address
+// immediately. The parent keeps running - callers should Shutdown it
+// afterwards to drain in-flight requests.
+func (s *Server) Restart() error {
+	tcpListener, ok := s.raw.(*net.TCPListener)
+	if !ok {
+		return fmt.Errorf("listener doesn't support restart: not backed by a *net.TCPListener")
+	}
+	listenerFile, err := tcpListener.File()
+	if err != nil {
+		return fmt.Errorf("couldn't get listener file descriptor: %v", err)
+	}
+	defer listenerFile.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("couldn't determine current executable: %v", err)
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{listenerFile}
+	cmd.Env = append(os.Environ(), listenerFDEnvVar+"=1")
+
+	return cmd.Start()
+}
+
+// Run starts serving in the background and blocks until one of:
+//   - SIGINT/SIGTERM: shut down gracefully and return
+//   - SIGUSR1: re-exec a replacement process, then drain and shut down
+//   - Serve itself returns (e.g. a listener error)
+//
+// It's the top-level entry point main is expected to call.
+func (s *Server) Run() error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1)
+	defer signal.Stop(sigCh)
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.Serve() }()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case sig := <-sigCh:
+		if sig == syscall.SIGUSR1 {
+			if err := s.Restart(); err != nil {
+				return fmt.Errorf("restart failed, keeping existing process: %v", err)
+			}
+		}
+		if err := s.Shutdown(); err != nil {
+			return fmt.Errorf("shutdown: %v", err)
+		}
+		return <-serveErr
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// selfSignedTLSConfig builds a minimal tls.Config good enough for serving
+// (and for an http.Client with InsecureSkipVerify to talk to).
+func selfSignedTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating cert: %v", err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}
+}
+
+func TestServeAndShutdown(t *testing.T) {
+	handlerDone := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprintln(w, "ok")
+		close(handlerDone)
+	})
+
+	srv, err := New("127.0.0.1:0", selfSignedTLSConfig(t), mux, 5*time.Second)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	addr := srv.raw.Addr().String()
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve() }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+	reqDone := make(chan error, 1)
+	go func() {
+		resp, err := client.Get("https://" + addr + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		reqDone <- err
+	}()
+
+	// Give the request a moment to reach the handler before we shut down,
+	// so Shutdown has to wait on it rather than racing it.
+	time.Sleep(50 * time.Millisecond)
+
+	if err := srv.Shutdown(); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	select {
+	case <-handlerDone:
+	default:
+		t.Fatal("Shutdown returned before the in-flight handler finished")
+	}
+
+	if err := <-reqDone; err != nil {
+		t.Errorf("in-flight request failed: %v", err)
+	}
+	if err := <-serveErr; err != nil {
+		t.Errorf("Serve returned an error: %v", err)
+	}
+}
+
+// TestRunGracefulOnSIGTERM drives Run via an in-process signal, the way
+// DBHub itself would be signalled in production.
+func TestRunGracefulOnSIGTERM(t *testing.T) {
+	srv, err := New("127.0.0.1:0", selfSignedTLSConfig(t), http.NewServeMux(), time.Second)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- srv.Run() }()
+
+	// Give Run a moment to install its signal handler before we send one.
+	time.Sleep(50 * time.Millisecond)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after SIGTERM")
+	}
+}
+
+func TestShutdownRunsOnShutdownHook(t *testing.T) {
+	srv, err := New("127.0.0.1:0", selfSignedTLSConfig(t), http.NewServeMux(), time.Second)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var hookRan bool
+	srv.OnShutdown(func() error {
+		hookRan = true
+		return nil
+	})
+
+	go srv.Serve()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := srv.Shutdown(); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if !hookRan {
+		t.Error("Shutdown didn't run the registered OnShutdown hook")
+	}
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FSConfig holds the connection parameters for the filesystem driver, handy
+// for dev and single-node deployments that don't want to run Minio.
+type FSConfig struct {
+	Root string
+}
+
+// fsBackend implements Backend by laying objects out as
+// <root>/<bucket>/<object> on the local filesystem.
+type fsBackend struct {
+	root string
+}
+
+func newFSBackend(cfg FSConfig) (Backend, error) {
+	if cfg.Root == "" {
+		return nil, fmt.Errorf("storage.fs.root must be set")
+	}
+	if err := os.MkdirAll(cfg.Root, 0750); err != nil {
+		return nil, fmt.Errorf("couldn't create storage root %s: %v", cfg.Root, err)
+	}
+	return &fsBackend{root: cfg.Root}, nil
+}
+
+func (b *fsBackend) path(bucket, object string) string {
+	return filepath.Join(b.root, bucket, object)
+}
+
+func (b *fsBackend) Get(bucket, object string) (io.ReadCloser, error) {
+	return os.Open(b.path(bucket, object))
+}
+
+func (b *fsBackend) Put(bucket, object string, data io.Reader, size int64, contentType string) error {
+	path := b.path(bucket, object)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, data)
+	return err
+}
+
+func (b *fsBackend) Stat(bucket, object string) (ObjectInfo, error) {
+	info, err := os.Stat(b.path(bucket, object))
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: object, Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+func (b *fsBackend) Delete(bucket, object string) error {
+	return os.Remove(b.path(bucket, object))
+}
+
+func (b *fsBackend) List(bucket, prefix string) ([]ObjectInfo, error) {
+	bucketDir := filepath.Join(b.root, bucket)
+	var objects []ObjectInfo
+	err := filepath.Walk(bucketDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		key, err := filepath.Rel(bucketDir, path)
+		if err != nil {
+			return err
+		}
+		if prefix != "" && !hasPrefix(key, prefix) {
+			return nil
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func hasPrefix(key, prefix string) bool {
+	return len(key) >= len(prefix) && key[:len(prefix)] == prefix
+}
+
+// PresignedGet has no filesystem equivalent of a time-limited signed URL,
+// so it returns a plain file:// URL instead - fine for the dev/single-node
+// use case this driver targets.
+func (b *fsBackend) PresignedGet(bucket, object string, expiry time.Duration) (string, error) {
+	path := b.path(bucket, object)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return "file://" + path, nil
+}
+
+func (b *fsBackend) HealthCheck() error {
+	_, err := ioutil.ReadDir(b.root)
+	return err
+}
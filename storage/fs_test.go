@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFSBackendRoundTrip(t *testing.T) {
+	root, err := ioutil.TempDir("", "dbhub-storage")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	backend, err := New(Config{Driver: "fs", FS: FSConfig{Root: root}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	content := []byte("hello dbhub")
+	if err := backend.Put("mybucket", "dir/object.txt", bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	info, err := backend.Stat("mybucket", "dir/object.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != int64(len(content)) {
+		t.Errorf("Stat size = %d, want %d", info.Size, len(content))
+	}
+
+	rc, err := backend.Get("mybucket", "dir/object.txt")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("reading object: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Get returned %q, want %q", got, content)
+	}
+
+	objects, err := backend.List("mybucket", "dir/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "dir/object.txt" {
+		t.Errorf("List = %+v, want single entry dir/object.txt", objects)
+	}
+
+	if err := backend.Delete("mybucket", "dir/object.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := backend.Stat("mybucket", "dir/object.txt"); err == nil {
+		t.Error("Stat after Delete: expected an error, got none")
+	}
+}
+
+func TestNewUnknownDriver(t *testing.T) {
+	if _, err := New(Config{Driver: "bogus"}); err == nil {
+		t.Error("New with an unknown driver: expected an error, got none")
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"io"
+	"time"
+
+	"github.com/minio/minio-go"
+)
+
+// MinioConfig holds the connection parameters for the Minio driver.
+type MinioConfig struct {
+	Server    string
+	AccessKey string
+	Secret    string
+	HTTPS     bool
+}
+
+// minioBackend implements Backend on top of a Minio (or any S3-compatible,
+// non-AWS) endpoint.
+type minioBackend struct {
+	client *minio.Client
+}
+
+func newMinioBackend(cfg MinioConfig) (Backend, error) {
+	client, err := minio.New(cfg.Server, cfg.AccessKey, cfg.Secret, cfg.HTTPS)
+	if err != nil {
+		return nil, err
+	}
+	return &minioBackend{client: client}, nil
+}
+
+func (b *minioBackend) Get(bucket, object string) (io.ReadCloser, error) {
+	return b.client.GetObject(bucket, object)
+}
+
+func (b *minioBackend) Put(bucket, object string, data io.Reader, size int64, contentType string) error {
+	_, err := b.client.PutObject(bucket, object, data, contentType)
+	return err
+}
+
+func (b *minioBackend) Stat(bucket, object string) (ObjectInfo, error) {
+	info, err := b.client.StatObject(bucket, object)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: info.Key, Size: info.Size, LastModified: info.LastModified, ContentType: info.ContentType}, nil
+}
+
+func (b *minioBackend) Delete(bucket, object string) error {
+	return b.client.RemoveObject(bucket, object)
+}
+
+func (b *minioBackend) List(bucket, prefix string) ([]ObjectInfo, error) {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	var objects []ObjectInfo
+	for info := range b.client.ListObjects(bucket, prefix, true, doneCh) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		objects = append(objects, ObjectInfo{Key: info.Key, Size: info.Size, LastModified: info.LastModified, ContentType: info.ContentType})
+	}
+	return objects, nil
+}
+
+func (b *minioBackend) PresignedGet(bucket, object string, expiry time.Duration) (string, error) {
+	u, err := b.client.PresignedGetObject(bucket, object, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (b *minioBackend) HealthCheck() error {
+	_, err := b.client.ListBuckets()
+	return err
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go"
+)
+
+// S3Config holds the connection parameters for the real-AWS signature-v4
+// driver. Region must be set, as AWS (unlike Minio) rejects v4-signed
+// requests that don't specify the bucket's region.
+type S3Config struct {
+	Region    string
+	Bucket    string
+	AccessKey string
+	Secret    string
+}
+
+// s3Backend implements Backend against real AWS S3, using v4 signing via
+// minio-go's region-aware client constructor.
+type s3Backend struct {
+	client *minio.Client
+	bucket string // probed by HealthCheck; all other methods take their bucket per call
+}
+
+func newS3Backend(cfg S3Config) (Backend, error) {
+	client, err := minio.NewWithRegion("s3.amazonaws.com", cfg.AccessKey, cfg.Secret, true, cfg.Region)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Backend{client: client, bucket: cfg.Bucket}, nil
+}
+
+func (b *s3Backend) Get(bucket, object string) (io.ReadCloser, error) {
+	return b.client.GetObject(bucket, object)
+}
+
+func (b *s3Backend) Put(bucket, object string, data io.Reader, size int64, contentType string) error {
+	_, err := b.client.PutObject(bucket, object, data, contentType)
+	return err
+}
+
+func (b *s3Backend) Stat(bucket, object string) (ObjectInfo, error) {
+	info, err := b.client.StatObject(bucket, object)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: info.Key, Size: info.Size, LastModified: info.LastModified, ContentType: info.ContentType}, nil
+}
+
+func (b *s3Backend) Delete(bucket, object string) error {
+	return b.client.RemoveObject(bucket, object)
+}
+
+func (b *s3Backend) List(bucket, prefix string) ([]ObjectInfo, error) {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	var objects []ObjectInfo
+	for info := range b.client.ListObjects(bucket, prefix, true, doneCh) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		objects = append(objects, ObjectInfo{Key: info.Key, Size: info.Size, LastModified: info.LastModified, ContentType: info.ContentType})
+	}
+	return objects, nil
+}
+
+func (b *s3Backend) PresignedGet(bucket, object string, expiry time.Duration) (string, error) {
+	u, err := b.client.PresignedGetObject(bucket, object, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (b *s3Backend) HealthCheck() error {
+	exists, err := b.client.BucketExists(b.bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("bucket %q does not exist", b.bucket)
+	}
+	return nil
+}
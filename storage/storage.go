@@ -0,0 +1,70 @@
+// Package storage abstracts DBHub's object storage behind a single
+// Backend interface, so the rest of the codebase doesn't need to care
+// whether objects actually live in Minio, real AWS S3, or a plain
+// filesystem tree.
+package storage
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ObjectInfo describes a stored object, independent of which backend it
+// came from.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ContentType  string
+}
+
+// Backend is implemented by each storage driver (Minio, S3, filesystem, ...).
+type Backend interface {
+	// Get returns a reader for the contents of bucket/object. Callers must
+	// close the returned ReadCloser.
+	Get(bucket, object string) (io.ReadCloser, error)
+
+	// Put stores data (exactly size bytes) as bucket/object.
+	Put(bucket, object string, data io.Reader, size int64, contentType string) error
+
+	// Stat returns metadata for bucket/object without fetching its contents.
+	Stat(bucket, object string) (ObjectInfo, error)
+
+	// Delete removes bucket/object.
+	Delete(bucket, object string) error
+
+	// List returns every object in bucket whose key starts with prefix.
+	List(bucket, prefix string) ([]ObjectInfo, error)
+
+	// PresignedGet returns a URL that grants time-limited read access to
+	// bucket/object without further authentication.
+	PresignedGet(bucket, object string, expiry time.Duration) (string, error)
+
+	// HealthCheck verifies the backend is reachable and usable, so main can
+	// fail fast at startup regardless of which driver is configured.
+	HealthCheck() error
+}
+
+// Config selects and configures a storage driver. Exactly one of Minio, S3
+// or FS is used, per Driver.
+type Config struct {
+	Driver string // "minio", "s3v4" or "fs"
+	Minio  MinioConfig
+	S3     S3Config
+	FS     FSConfig
+}
+
+// New constructs the Backend selected by cfg.Driver.
+func New(cfg Config) (Backend, error) {
+	switch cfg.Driver {
+	case "minio":
+		return newMinioBackend(cfg.Minio)
+	case "s3v4":
+		return newS3Backend(cfg.S3)
+	case "fs":
+		return newFSBackend(cfg.FS)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q (want one of: minio, s3v4, fs)", cfg.Driver)
+	}
+}